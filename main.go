@@ -5,35 +5,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math"
-	"sort"
-	"strings"
+	"os"
 	"time"
 
-	amino "github.com/tendermint/go-amino"
 	tmtypes "github.com/tendermint/tendermint/types"
 
 	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 
-	"github.com/cosmos/launch/pkg"
+	"github.com/cosmos/launch/pkg/gentx"
+	"github.com/cosmos/launch/pkg/manifest"
+	"github.com/cosmos/launch/pkg/overrides"
+	"github.com/cosmos/launch/pkg/sanitize"
+	"github.com/cosmos/launch/pkg/source"
+	"github.com/cosmos/launch/pkg/vesting"
 )
 
 const (
-	// processed contributors files
-	icfJSON     = "accounts/icf/contributors.json"
-	privateJSON = "accounts/private/contributors.json"
-	publicJSON  = "accounts/public/contributors.json"
-
 	// seperate because vesting
 	aibEmployeeJSON = "accounts/aib/employees.json"
 	aibMultisigJSON = "accounts/aib/multisig.json"
 
-	genesisTemplate = "params/genesis_template.json"
-	genesisFile     = "penultimate_genesis.json"
+	launchConfig        = "launch.toml"
+	auditLogFile        = "contributors.audit.json"
+	genesisTemplate     = "params/genesis_template.json"
+	overridesJSON       = "params/overrides.json"
+	gentxsDir           = "gentxs"
+	genesisFile         = "penultimate_genesis.json"
+	genesisManifestFile = "genesis.manifest.json"
+	genesisLockFile     = "genesis.lock"
+
+	// votingPowerSummaryTopN is how many validators the post-gentx summary
+	// names individually; the total power and the Nakamoto coefficient are
+	// computed over all of them.
+	votingPowerSummaryTopN = 10
 
 	atomDenomination    = "uatom"
-	atomGenesisTotal    = 236198958.12
+	atomGenesisTotalStr = "236198958.12"
 	addressGenesisTotal = 984
 
 	timeGenesisString = "2019-03-13 23:00:00 -0000 UTC"
@@ -43,10 +51,10 @@ const (
 var (
 	timeGenesis time.Time
 
-	// vesting times
-	timeGenesisTwoMonths time.Time
-	timeGenesisOneYear   time.Time
-	timeGenesisTwoYears  time.Time
+	// atomGenesisTotal is atomGenesisTotalStr parsed once into an exact
+	// sdk.Dec, instead of a float64 literal that has to round-trip
+	// through IEEE-754 every time it's used.
+	atomGenesisTotal sdk.Dec
 )
 
 // initialize the times!
@@ -57,103 +65,125 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	timeGenesisTwoMonths = timeGenesis.AddDate(0, 2, 0)
-	timeGenesisOneYear = timeGenesis.AddDate(1, 0, 0)
-	timeGenesisTwoYears = timeGenesis.AddDate(2, 0, 0)
+	atomGenesisTotal = sdk.MustNewDecFromStr(atomGenesisTotalStr)
 }
 
 // max precision on amt is two decimals ("centi-atoms")
-func atomToUAtomInt(amt float64) sdk.Int {
-	// amt is specified to 2 decimals ("centi-atoms").
-	// multiply by 100 to get the number of centi-atoms
-	// and round to int64.
-	// Multiply by remaining to get uAtoms.
-	var precision float64 = 100
-	var remaining int64 = 10000
-
-	catoms := int64(math.Round(amt * precision))
-	uAtoms := catoms * remaining
-	return sdk.NewInt(uAtoms)
+func atomToUAtomInt(amt sdk.Dec) sdk.Int {
+	// amt is specified to 2 decimals ("centi-atoms"); 1 atom == 1e6
+	// uatom, so this multiply-then-truncate is exact for any centi-atom
+	// input. Using sdk.Dec (18 digits of internal precision) instead of
+	// float64 means two runs over the same input always produce the
+	// same uAtoms, with no IEEE-754 rounding in between.
+	uatomPerAtom := sdk.NewDec(1000000)
+	return amt.Mul(uatomPerAtom).TruncateInt()
 }
 
 // convert atoms with two decimal precision to coins
-func newCoins(amt float64) sdk.Coins {
-	uAtoms := atomToUAtomInt(amt)
+func newCoins(amt sdk.Dec) sdk.Coins {
+	return coinsFor(atomDenomination, amt)
+}
+
+// convert an amount with two decimal precision to coins of an arbitrary
+// denom, for contributor sources that don't all allocate uatom.
+func coinsFor(denom string, amt sdk.Dec) sdk.Coins {
 	return sdk.Coins{
 		sdk.Coin{
-			Denom:  atomDenomination,
-			Amount: uAtoms,
+			Denom:  denom,
+			Amount: atomToUAtomInt(amt),
 		},
 	}
 }
 
 func main() {
-	// for each path, accumulate the contributors file
-	// icf addresses are in bech32, fundraiser are in hex
-	contribs := make(map[string]float64)
-	{
-		accumulateBechContributors(icfJSON, contribs)
-		accumulateHexContributors(privateJSON, contribs)
-		accumulateHexContributors(publicJSON, contribs)
+	// load the registered contributor sources (bech32/hex JSON, eth-alloc,
+	// csv, signed-claim, ...) declared in launch.toml, merge their
+	// allocations, and write an audit log of where each came from.
+	sources, err := source.LoadConfig(launchConfig)
+	if err != nil {
+		panic(err)
+	}
+	contribs, err := source.Collect(sources)
+	if err != nil {
+		panic(err)
+	}
+	if err := source.WriteAuditLog(auditLogFile, contribs); err != nil {
+		panic(err)
+	}
+
+	// index by address for the aib duplicate check below
+	contribsIndex := make(map[string]struct{}, len(contribs))
+	for _, c := range contribs {
+		contribsIndex[c.Address] = struct{}{}
 	}
 
 	// load the aib pieces
-	employees, multisig := aibAtoms(aibEmployeeJSON, aibMultisigJSON, contribs)
+	employees, multisig := aibAtoms(aibEmployeeJSON, aibMultisigJSON, contribsIndex)
+
+	// anchors centralizes the time-anchor math (two months, one year, two
+	// years out from genesis) that every vesting grant below resolves its
+	// Start/End against.
+	anchors := vesting.NewAnchors(timeGenesis)
 
 	// construct the genesis accounts :)
 	var genesisAccounts []gaia.GenesisAccount
 	{
-		for addr, amt := range contribs {
-			acc := gaia.GenesisAccount{
-				Address: fromBech32(addr),
-				Coins:   newCoins(amt),
+		for _, c := range contribs {
+			newCoinsForDenom := func(amt sdk.Dec) sdk.Coins { return coinsFor(c.Denom, amt) }
+
+			if c.Vesting.Schedule == "" {
+				genesisAccounts = append(genesisAccounts, gaia.GenesisAccount{
+					Address: fromBech32(c.Address),
+					Coins:   newCoinsForDenom(c.Amount),
+				})
+				continue
+			}
+
+			grant := c.Vesting
+			grant.Amount = c.Amount
+			genAcc, err := vesting.Build(grant, anchors, fromBech32(c.Address), newCoinsForDenom)
+			if err != nil {
+				panic(err)
 			}
-			genesisAccounts = append(genesisAccounts, acc)
+			genesisAccounts = append(genesisAccounts, genAcc)
 		}
 
-		// add aib employees vesting for 1 year cliff
-		for _, aibAcc := range employees {
-			coins := newCoins(aibAcc.Amount)
-			genAcc := gaia.GenesisAccount{
-				Address:         fromBech32(aibAcc.Address),
-				Coins:           coins,
-				OriginalVesting: coins,
-				EndTime:         timeGenesisOneYear.Unix(),
+		// add aib employee grants; absent a `schedule` in employees.json
+		// this defaults to the original 1 year cliff.
+		for _, emp := range employees {
+			grant := emp.Grant
+			if grant.Schedule == "" {
+				grant.Schedule = vesting.ScheduleDelayed
+				grant.End = "one_year"
+			}
+			genAcc, err := vesting.Build(grant, anchors, fromBech32(grant.Address), newCoins)
+			if err != nil {
+				panic(err)
 			}
 			genesisAccounts = append(genesisAccounts, genAcc)
 		}
 
-		// add aib multisig vesting continuosuly for 2 years
-		// starting after 2 months
-		multisigCoins := newCoins(multisig.Amount)
-		genAcc := gaia.GenesisAccount{
-			Address:         fromBech32(multisig.Address),
-			Coins:           multisigCoins,
-			OriginalVesting: multisigCoins,
-			StartTime:       timeGenesisTwoMonths.Unix(),
-			EndTime:         timeGenesisTwoYears.Unix(),
+		// add the aib multisig grant; absent a `schedule` in
+		// multisig.json this defaults to the original 2 year continuous
+		// vest starting 2 months after genesis.
+		grant := multisig.Grant
+		if grant.Schedule == "" {
+			grant.Schedule = vesting.ScheduleContinuous
+			grant.Start = "two_months"
+			grant.End = "two_years"
+		}
+		genAcc, err := vesting.Build(grant, anchors, fromBech32(grant.Address), newCoins)
+		if err != nil {
+			panic(err)
 		}
 		genesisAccounts = append(genesisAccounts, genAcc)
 	}
 
-	// check uAtom total
-	uAtomTotal := sdk.NewInt(0)
-	for _, account := range genesisAccounts {
-		uAtomTotal = uAtomTotal.Add(account.Coins[0].Amount)
-	}
-	if !uAtomTotal.Equal(atomToUAtomInt(atomGenesisTotal)) {
-		panicStr := fmt.Sprintf("expected %s atoms, got %s atoms allocated in genesis", atomToUAtomInt(atomGenesisTotal), uAtomTotal.String())
-		panic(panicStr)
-	}
 	if len(genesisAccounts) != addressGenesisTotal {
 		panicStr := fmt.Sprintf("expected %d addresses, got %d addresses allocated in genesis", addressGenesisTotal, len(genesisAccounts))
 		panic(panicStr)
 	}
 
-	fmt.Println("-----------")
-	fmt.Println("TOTAL addrs", len(genesisAccounts))
-	fmt.Println("TOTAL uAtoms", uAtomTotal.String())
-
 	// ensure no duplicates
 	{
 		checkdupls := make(map[string]struct{})
@@ -168,19 +198,47 @@ func main() {
 		}
 	}
 
-	// sort the accounts
-	sort.SliceStable(genesisAccounts, func(i, j int) bool {
-		return strings.Compare(
-			genesisAccounts[i].Address.String(),
-			genesisAccounts[j].Address.String(),
-		) < 0
-	})
+	// sort accounts and their coin sets, and validate every account
+	// (positive coins, no repeated denom, sane vesting schedules, bech32
+	// round-trip, exact supply) before it's written into the genesis doc.
+	//
+	// atomGenesisTotal is the chain's own historical total; any source
+	// allocating a different denom (CSV's `denom` column, eth-alloc) has to
+	// declare its own expect_supply in launch.toml, since there's no fixed
+	// total main otherwise knows to check it against.
+	expectedSupply := map[string]sdk.Int{atomDenomination: atomToUAtomInt(atomGenesisTotal)}
+	for _, src := range sources {
+		denom, amt, ok := src.ExpectedSupply()
+		if !ok {
+			continue
+		}
+		cur, ok := expectedSupply[denom]
+		if !ok {
+			cur = sdk.NewInt(0)
+		}
+		expectedSupply[denom] = cur.Add(amt)
+	}
+	genesisAccounts, err = sanitize.Sanitize(genesisAccounts, expectedSupply)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("-----------")
+	fmt.Println("TOTAL addrs", len(genesisAccounts))
+	fmt.Println("TOTAL uAtoms", expectedSupply[atomDenomination].String())
 
 	var genesisDoc *tmtypes.GenesisDoc
+	// gentxFiles collects the paths CollectDir actually read, so the
+	// manifest step below can hash them alongside the rest of the inputs.
+	var gentxFiles []string
 	// XXX: this is a bit much. is there something we can more easily resuse here?
-	// and do we need to register amino here?
 	// Note the app state is decoded using amino (ints are strings, anything else ?)
-	cdc := amino.NewCodec()
+	//
+	// gaia.MakeCodec(), not a bare amino.NewCodec(): gentx collection below
+	// decodes StdTx (sdk.Msg, for MsgCreateValidator) and crypto.PubKey,
+	// both registered interfaces. A bare codec errors "unregistered
+	// interface" on the first gentx.
+	cdc := gaia.MakeCodec()
 	{
 		// read the template with the params
 		var err error
@@ -200,6 +258,52 @@ func main() {
 		}
 		genesisState.Accounts = genesisAccounts
 
+		// layer in any operator-supplied module param overrides (staking,
+		// gov, mint, distr, slashing, crisis) on top of the template
+		// defaults, re-validating each module we touch.
+		overridesCfg, err := overrides.Load(overridesJSON)
+		if err != nil {
+			panic(err)
+		}
+		if overridesCfg != nil {
+			if err := overrides.Apply(cdc, &genesisState, overridesCfg); err != nil {
+				panic(err)
+			}
+		}
+
+		// collect signed gentxs (one MsgCreateValidator StdTx per file) out
+		// of gentxsDir, validating each delegator's self-delegation against
+		// the genesis balances just assembled, then merge them into GenTxs.
+		// StakingData is not changed here; the stake is bonded when InitChain
+		// delivers the gentxs. A missing gentxs directory just means
+		// this genesis has no validators yet (e.g. an earlier launch stage).
+		if _, err := os.Stat(gentxsDir); err == nil {
+			balances := make(map[string]sdk.Coins, len(genesisState.Accounts))
+			for _, acc := range genesisState.Accounts {
+				balances[acc.Address.String()] = acc.Coins
+			}
+
+			gentxList, rawTxs, err := gentx.CollectDir(cdc, gentxsDir, genesisDoc.ChainID, balances)
+			if err != nil {
+				panic(err)
+			}
+			gentx.Apply(&genesisState, rawTxs)
+			for _, g := range gentxList {
+				gentxFiles = append(gentxFiles, g.File)
+			}
+
+			summary := gentx.Summarize(gentxList, votingPowerSummaryTopN)
+			fmt.Println("-----------")
+			fmt.Println("TOTAL gentxs", len(gentxList))
+			fmt.Println("TOTAL voting power", summary.TotalPower)
+			fmt.Println("Nakamoto coefficient", summary.NakamotoCoefficient)
+			for _, g := range summary.Top {
+				fmt.Printf("  %s (%s) power=%d\n", g.Validator, g.Moniker, g.VotingPower)
+			}
+		} else if !os.IsNotExist(err) {
+			panic(err)
+		}
+
 		// marshal the gaia app state back to json and update the genesisDoc
 		genesisStateJSON, err := cdc.MarshalJSON(genesisState)
 		if err != nil {
@@ -225,6 +329,35 @@ func main() {
 			panic(err)
 		}
 	}
+
+	// record a reproducibility manifest: the sha256 of every input file,
+	// this tool's git commit, and the sha256 of the genesis it just
+	// produced, then check it against any checked-in genesis.lock.
+	{
+		inputFiles := []string{launchConfig, aibEmployeeJSON, aibMultisigJSON, genesisTemplate}
+		for _, src := range sources {
+			inputFiles = append(inputFiles, src.Files()...)
+		}
+		if _, err := ioutil.ReadFile(overridesJSON); err == nil {
+			inputFiles = append(inputFiles, overridesJSON)
+		}
+		// every gentx CollectDir actually read also shapes the produced
+		// genesis (it's merged into GenTxs, and bonds stake once InitChain
+		// delivers it), so a swapped or added gentx has to show up in the
+		// manifest too.
+		inputFiles = append(inputFiles, gentxFiles...)
+
+		m, err := manifest.Build(inputFiles, genesisFile)
+		if err != nil {
+			panic(err)
+		}
+		if err := manifest.CheckLock(genesisLockFile, m); err != nil {
+			panic(err)
+		}
+		if err := manifest.Write(genesisManifestFile, m); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func fromBech32(address string) sdk.AccAddress {
@@ -239,55 +372,68 @@ func fromBech32(address string) sdk.AccAddress {
 	return sdk.AccAddress(bz)
 }
 
-// load a map of hex addresses and convert them to bech32
-func accumulateHexContributors(fileName string, contribs map[string]float64) error {
-	allocations := pkg.ObjToMap(fileName)
-
-	for addr, amt := range allocations {
-		bech32Addr, err := sdk.AccAddressFromHex(addr)
-		if err != nil {
-			return err
-		}
-		addr = bech32Addr.String()
+//----------------------------------------------------------
+// AiB Data
 
-		if _, ok := contribs[addr]; ok {
-			fmt.Println("Duplicate addr", addr)
-		}
-		contribs[addr] += amt
-	}
-	return nil
+// Employee is one row of accounts/aib/employees.json. It embeds
+// vesting.Grant so a row can opt into any supported schedule; omitting
+// `schedule` falls back to the original 1 year cliff.
+type Employee struct {
+	vesting.Grant
+	Lock string `json:"lock"`
 }
 
-func accumulateBechContributors(fileName string, contribs map[string]float64) error {
-	allocations := pkg.ObjToMap(fileName)
-
-	for addr, amt := range allocations {
-		if _, ok := contribs[addr]; ok {
-			fmt.Println("Duplicate addr", addr)
-		}
-		contribs[addr] += amt
+// UnmarshalJSON shadows the embedded Grant's "amount" field with a raw
+// message before decoding, because employees.json carries amount as a
+// bare JSON number and sdk.Dec.UnmarshalJSON only accepts a quoted
+// decimal string. See vesting.ParseAmount.
+func (e *Employee) UnmarshalJSON(bz []byte) error {
+	type alias Employee
+	aux := struct {
+		Amount json.RawMessage `json:"amount"`
+		*alias
+	}{alias: (*alias)(e)}
+	if err := json.Unmarshal(bz, &aux); err != nil {
+		return err
+	}
+	amt, err := vesting.ParseAmount(aux.Amount)
+	if err != nil {
+		return fmt.Errorf("employee %s: %v", e.Address, err)
 	}
+	e.Amount = amt
 	return nil
 }
 
-//----------------------------------------------------------
-// AiB Data
-
-type Account struct {
-	Address string  `json:"addr"`
-	Amount  float64 `json:"amount"`
-	Lock    string  `json:"lock"`
-}
-
-type MultisigAccount struct {
-	Address   string   `json:"addr"`
+// Multisig is accounts/aib/multisig.json. It embeds vesting.Grant for the
+// same reason as Employee; Threshold/Pubs describe the multisig key itself
+// and aren't consumed by the vesting builder.
+type Multisig struct {
+	vesting.Grant
 	Threshold int      `json:"threshold"`
 	Pubs      []string `json:"pubs"`
-	Amount    float64  `json:"amount"`
+}
+
+// UnmarshalJSON shadows the embedded Grant's "amount" field for the same
+// reason as Employee.UnmarshalJSON: multisig.json also predates sdk.Dec.
+func (m *Multisig) UnmarshalJSON(bz []byte) error {
+	type alias Multisig
+	aux := struct {
+		Amount json.RawMessage `json:"amount"`
+		*alias
+	}{alias: (*alias)(m)}
+	if err := json.Unmarshal(bz, &aux); err != nil {
+		return err
+	}
+	amt, err := vesting.ParseAmount(aux.Amount)
+	if err != nil {
+		return fmt.Errorf("multisig %s: %v", m.Address, err)
+	}
+	m.Amount = amt
+	return nil
 }
 
 // load the aib atoms and ensure there are no duplicates with the contribs
-func aibAtoms(employeesFile, multisigFile string, contribs map[string]float64) (employees []Account, multisigAcc MultisigAccount) {
+func aibAtoms(employeesFile, multisigFile string, contribs map[string]struct{}) (employees []Employee, multisigAcc Multisig) {
 	bz, err := ioutil.ReadFile(employeesFile)
 	if err != nil {
 		panic(err)
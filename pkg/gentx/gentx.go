@@ -0,0 +1,195 @@
+// Package gentx scans a directory of signed gentx files (one
+// MsgCreateValidator StdTx per file, the output of `gaiad gentx`) and turns
+// them into the validator-set half of genesis, the half this tool otherwise
+// leaves for launch coordinators to assemble by hand. It's modeled on the
+// SDK's genutil.CollectGenTxs, but validates against the genesis balances
+// this tool already assembled rather than trusting the gentx at face value.
+package gentx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	amino "github.com/tendermint/go-amino"
+
+	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	auth "github.com/cosmos/cosmos-sdk/x/auth"
+	staking "github.com/cosmos/cosmos-sdk/x/staking"
+)
+
+// powerReduction mirrors the SDK's default: consensus voting power is
+// bonded uatom divided down by 1e6, truncated.
+var powerReduction = sdk.NewInt(1000000)
+
+// GenTx is one validated gentx: the bits CollectDir's callers need to
+// report on voting power, without re-decoding the raw tx.
+type GenTx struct {
+	File        string
+	Moniker     string
+	Delegator   sdk.AccAddress
+	Validator   sdk.ValAddress
+	PubKey      string
+	BondedCoin  sdk.Coin
+	VotingPower int64
+}
+
+// CollectDir reads every *.json file in dir, verifies it's a single-message
+// StdTx carrying a MsgCreateValidator signed by the delegator it names, and
+// that the delegator's genesis balance (looked up in balances) covers the
+// self-delegation. It returns the validated gentxs and their raw tx bytes,
+// both sorted by validator address so the merged output is deterministic.
+//
+// A gentx that fails any check is a hard error: a bad gentx silently
+// dropped would publish a genesis with a validator set different from what
+// coordinators reviewed.
+func CollectDir(cdc *amino.Codec, dir string, chainID string, balances map[string]sdk.Coins) ([]GenTx, []json.RawMessage, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("gentx: reading %s: %v", dir, err)
+	}
+
+	var gentxs []GenTx
+	rawByValidator := make(map[string]json.RawMessage)
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		bz, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gentx: %s: %v", path, err)
+		}
+
+		var tx auth.StdTx
+		if err := cdc.UnmarshalJSON(bz, &tx); err != nil {
+			return nil, nil, fmt.Errorf("gentx: %s: not a valid StdTx: %v", path, err)
+		}
+
+		g, err := validate(tx, chainID, balances)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gentx: %s: %v", path, err)
+		}
+		g.File = path
+
+		valStr := g.Validator.String()
+		if _, dup := rawByValidator[valStr]; dup {
+			return nil, nil, fmt.Errorf("gentx: %s: duplicate gentx for validator %s", path, valStr)
+		}
+		rawByValidator[valStr] = json.RawMessage(bz)
+		gentxs = append(gentxs, g)
+	}
+
+	sort.Slice(gentxs, func(i, j int) bool {
+		return gentxs[i].Validator.String() < gentxs[j].Validator.String()
+	})
+
+	rawTxs := make([]json.RawMessage, len(gentxs))
+	for i, g := range gentxs {
+		rawTxs[i] = rawByValidator[g.Validator.String()]
+	}
+
+	return gentxs, rawTxs, nil
+}
+
+func validate(tx auth.StdTx, chainID string, balances map[string]sdk.Coins) (GenTx, error) {
+	if len(tx.Msgs) != 1 {
+		return GenTx{}, fmt.Errorf("expected exactly one message, got %d", len(tx.Msgs))
+	}
+	msg, ok := tx.Msgs[0].(staking.MsgCreateValidator)
+	if !ok {
+		return GenTx{}, fmt.Errorf("expected MsgCreateValidator, got %T", tx.Msgs[0])
+	}
+	if err := msg.ValidateBasic(); err != nil {
+		return GenTx{}, fmt.Errorf("invalid MsgCreateValidator: %v", err)
+	}
+
+	if len(tx.Signatures) != 1 {
+		return GenTx{}, fmt.Errorf("expected exactly one signature, got %d", len(tx.Signatures))
+	}
+	sig := tx.Signatures[0]
+	if sdk.AccAddress(sig.PubKey.Address()).String() != msg.DelegatorAddress.String() {
+		return GenTx{}, fmt.Errorf("signature pub_key does not derive the delegator address %s", msg.DelegatorAddress)
+	}
+	signBytes := auth.StdSignBytes(chainID, 0, 0, tx.Fee, tx.Msgs, tx.Memo)
+	if !sig.PubKey.VerifyBytes(signBytes, sig.Signature) {
+		return GenTx{}, fmt.Errorf("signature does not verify for delegator %s", msg.DelegatorAddress)
+	}
+
+	held, ok := balances[msg.DelegatorAddress.String()]
+	if !ok {
+		return GenTx{}, fmt.Errorf("delegator %s has no genesis balance", msg.DelegatorAddress)
+	}
+	if held.AmountOf(msg.Value.Denom).LT(msg.Value.Amount) {
+		return GenTx{}, fmt.Errorf("delegator %s self-delegates %s but only has %s%s at genesis",
+			msg.DelegatorAddress, msg.Value, held.AmountOf(msg.Value.Denom), msg.Value.Denom)
+	}
+
+	return GenTx{
+		Moniker:     msg.Description.Moniker,
+		Delegator:   msg.DelegatorAddress,
+		Validator:   msg.ValidatorAddress,
+		PubKey:      sdk.MustBech32ifyConsPub(msg.PubKey),
+		BondedCoin:  msg.Value,
+		VotingPower: msg.Value.Amount.Div(powerReduction).Int64(),
+	}, nil
+}
+
+// Apply merges rawTxs into genesisState.GenTxs. It does not touch
+// StakingData itself: at InitChain the gentxs in GenTxs are delivered as
+// real MsgCreateValidator txs, which is what actually bonds the stake and
+// creates the matching Validators/Delegations entries. Pre-adding to
+// Pool.BondedTokens here would double-count it, tripping the bonded-pool
+// invariant once InitChain runs -- same as genutil.CollectGenTxs, which
+// also leaves the pool alone.
+func Apply(genesisState *gaia.GenesisState, rawTxs []json.RawMessage) {
+	genesisState.GenTxs = rawTxs
+}
+
+// Summary reports the voting power distribution a set of gentxs produces,
+// so a launch coordinator can spot concentration before publishing genesis.
+type Summary struct {
+	TotalPower          int64
+	Top                 []GenTx // top N by voting power, descending
+	NakamotoCoefficient int     // smallest number of validators whose combined power exceeds 1/3 of total
+}
+
+// Summarize ranks gentxs by voting power and computes the Nakamoto
+// coefficient: the fewest validators that, colluding, could halt the chain
+// by controlling more than a third of total voting power.
+func Summarize(gentxs []GenTx, topN int) Summary {
+	ranked := make([]GenTx, len(gentxs))
+	copy(ranked, gentxs)
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].VotingPower > ranked[j].VotingPower })
+
+	var total int64
+	for _, g := range ranked {
+		total += g.VotingPower
+	}
+
+	var running int64
+	coefficient := 0
+	for _, g := range ranked {
+		running += g.VotingPower
+		coefficient++
+		if running*3 > total {
+			break
+		}
+	}
+
+	top := ranked
+	if len(top) > topN {
+		top = top[:topN]
+	}
+
+	return Summary{
+		TotalPower:          total,
+		Top:                 top,
+		NakamotoCoefficient: coefficient,
+	}
+}
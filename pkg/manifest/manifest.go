@@ -0,0 +1,110 @@
+// Package manifest records what a genesis run actually consumed and
+// produced, so an independent verifier can reproduce and audit a launch
+// artifact with one command instead of trusting that two runs over the
+// "same" inputs really did produce the same output.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Manifest is the contents of genesis.manifest.json.
+type Manifest struct {
+	GitCommit   string            `json:"git_commit"`
+	InputHashes map[string]string `json:"input_hashes"` // path -> sha256 hex
+	GenesisHash string            `json:"genesis_hash"` // sha256 hex of the produced genesis file
+}
+
+// Build hashes every path in inputs and the produced genesis file at
+// genesisPath, and records the tool's own git commit.
+func Build(inputs []string, genesisPath string) (Manifest, error) {
+	hashes := make(map[string]string, len(inputs))
+	for _, path := range inputs {
+		h, err := hashFile(path)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("manifest: hashing %s: %v", path, err)
+		}
+		hashes[path] = h
+	}
+
+	genesisHash, err := hashFile(genesisPath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("manifest: hashing %s: %v", genesisPath, err)
+	}
+
+	return Manifest{
+		GitCommit:   gitCommit(),
+		InputHashes: hashes,
+		GenesisHash: genesisHash,
+	}, nil
+}
+
+func hashFile(path string) (string, error) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(bz)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// gitCommit shells out to `git rev-parse HEAD`. A checkout without git
+// (e.g. a tarball release) gets "unknown" rather than failing the run.
+func gitCommit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// Write writes m as indented JSON to path. encoding/json sorts map keys
+// when marshaling, so InputHashes comes out in the same order on every
+// run regardless of how it was populated.
+func Write(path string, m Manifest) error {
+	bz, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bz, 0600)
+}
+
+// CheckLock compares m against the checked-in lockfile at lockPath. A
+// missing lockfile is not an error -- that's the first run establishing
+// one. Any disagreement in the genesis hash or a previously-locked input's
+// hash fails loudly.
+func CheckLock(lockPath string, m Manifest) error {
+	bz, err := ioutil.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var locked Manifest
+	if err := json.Unmarshal(bz, &locked); err != nil {
+		return fmt.Errorf("manifest: parsing %s: %v", lockPath, err)
+	}
+
+	if locked.GenesisHash != m.GenesisHash {
+		return fmt.Errorf("manifest: %s disagrees on genesis hash: locked %s, got %s", lockPath, locked.GenesisHash, m.GenesisHash)
+	}
+	for path, want := range locked.InputHashes {
+		got, ok := m.InputHashes[path]
+		if !ok {
+			return fmt.Errorf("manifest: %s: input %s is locked but wasn't hashed this run", lockPath, path)
+		}
+		if got != want {
+			return fmt.Errorf("manifest: %s: input %s hash changed: locked %s, got %s", lockPath, path, want, got)
+		}
+	}
+	return nil
+}
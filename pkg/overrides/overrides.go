@@ -0,0 +1,218 @@
+// Package overrides lets an operator tweak per-module genesis parameters
+// (staking, gov, mint, distribution, slashing, crisis) without hand-editing
+// the genesis template or the launch binary itself.
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	amino "github.com/tendermint/go-amino"
+
+	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
+	crisis "github.com/cosmos/cosmos-sdk/x/crisis"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution"
+	gov "github.com/cosmos/cosmos-sdk/x/gov"
+	mint "github.com/cosmos/cosmos-sdk/x/mint"
+	slashing "github.com/cosmos/cosmos-sdk/x/slashing"
+	staking "github.com/cosmos/cosmos-sdk/x/staking"
+)
+
+// Config is the parsed contents of params/overrides.json. Each key names a
+// module field on the amino-decoded gaia.GenesisState (as it would appear in
+// the genesis app_state, e.g. "staking", "gov", "mint", "distr", "slashing",
+// "crisis") and maps to a raw JSON fragment that is merged onto the
+// template's value for that module before validation.
+type Config map[string]json.RawMessage
+
+// Load reads and parses path. If path does not exist, Load returns a nil
+// Config and no error so that supplying overrides stays optional.
+func Load(path string) (Config, error) {
+	bz, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(bz, &cfg); err != nil {
+		return nil, fmt.Errorf("overrides: parsing %s: %v", path, err)
+	}
+	return cfg, nil
+}
+
+// field binds a module name from Config to the GenesisState sub-struct it
+// overrides and the ValidateGenesis call that must pass once merged.
+type field struct {
+	target   interface{}
+	validate func() error
+}
+
+// fields returns the set of module names Apply knows how to override,
+// bound to genesisState so that unmarshaling into target mutates it in
+// place and validate closes over the freshly merged value.
+func fields(genesisState *gaia.GenesisState) map[string]field {
+	return map[string]field{
+		"staking": {
+			target:   &genesisState.StakingData,
+			validate: func() error { return staking.ValidateGenesis(genesisState.StakingData) },
+		},
+		"gov": {
+			target:   &genesisState.GovData,
+			validate: func() error { return gov.ValidateGenesis(genesisState.GovData) },
+		},
+		"mint": {
+			target:   &genesisState.MintData,
+			validate: func() error { return mint.ValidateGenesis(genesisState.MintData) },
+		},
+		"distr": {
+			target:   &genesisState.DistrData,
+			validate: func() error { return distr.ValidateGenesis(genesisState.DistrData) },
+		},
+		"slashing": {
+			target:   &genesisState.SlashingData,
+			validate: func() error { return slashing.ValidateGenesis(genesisState.SlashingData) },
+		},
+		"crisis": {
+			target:   &genesisState.CrisisData,
+			validate: func() error { return crisis.ValidateGenesis(genesisState.CrisisData) },
+		},
+	}
+}
+
+// Apply deep-merges cfg onto genesisState in place, module by module, then
+// re-validates every touched module via its own ValidateGenesis. An
+// override naming a module that Apply doesn't recognize is a hard error
+// rather than a silent no-op, since a typo'd key in overrides.json should
+// never fall back to the template default unnoticed.
+func Apply(cdc *amino.Codec, genesisState *gaia.GenesisState, cfg Config) error {
+	known := fields(genesisState)
+
+	for name, raw := range cfg {
+		f, ok := known[name]
+		if !ok {
+			return fmt.Errorf("overrides: unknown genesis module %q (known: staking, gov, mint, distr, slashing, crisis)", name)
+		}
+		if err := rejectUnknownFields(reflect.TypeOf(f.target), raw); err != nil {
+			return fmt.Errorf("overrides: %s: %v", name, err)
+		}
+		if err := cdc.UnmarshalJSON(raw, f.target); err != nil {
+			return fmt.Errorf("overrides: %s: %v", name, err)
+		}
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("overrides: %s: failed validation after override: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// rejectUnknownFields walks raw's JSON keys against t's exported fields,
+// recursing into nested objects and arrays, and errors on the first key
+// that doesn't correspond to a field. cdc.UnmarshalJSON (like encoding/json)
+// happily ignores a key it doesn't recognize, so on its own it would let a
+// misspelled leaf like "unbonding_timee" silently fall back to whatever the
+// template already had -- exactly the typo this is meant to catch.
+//
+// Fields whose static type is an interface (e.g. crypto.PubKey on a
+// validator) are left to amino: they're polymorphic by design and this
+// function has no type registry to check them against.
+func rejectUnknownFields(t reflect.Type, raw json.RawMessage) error {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() == reflect.Interface {
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !strings.HasPrefix(trimmed, "{") {
+			return nil // let the real unmarshal produce the type error
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil
+		}
+		known := jsonFieldTypes(t)
+		for key, val := range obj {
+			fieldType, ok := known[key]
+			if !ok {
+				return fmt.Errorf("unknown field %q", key)
+			}
+			if err := rejectUnknownFields(fieldType, val); err != nil {
+				return fmt.Errorf("%s.%v", key, err)
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if !strings.HasPrefix(trimmed, "[") {
+			return nil
+		}
+		var arr []json.RawMessage
+		if err := json.Unmarshal(raw, &arr); err != nil {
+			return nil
+		}
+		for i, elem := range arr {
+			if err := rejectUnknownFields(t.Elem(), elem); err != nil {
+				return fmt.Errorf("[%d]%v", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFieldTypes maps the JSON key each exported field of struct type t
+// decodes under (its json tag name, or its Go name with no tag) to that
+// field's type. Anonymous (embedded) fields are flattened in, matching how
+// encoding/json and amino promote them.
+func jsonFieldTypes(t reflect.Type) map[string]reflect.Type {
+	known := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" { // unexported
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := f.Name
+		if tag != "" {
+			if comma := strings.Index(tag, ","); comma >= 0 {
+				if tag[:comma] != "" {
+					name = tag[:comma]
+				}
+			} else {
+				name = tag
+			}
+		}
+
+		if f.Anonymous {
+			embedded := f.Type
+			for embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFieldTypes(embedded) {
+					known[k] = v
+				}
+				continue
+			}
+		}
+
+		known[name] = f.Type
+	}
+	return known
+}
@@ -0,0 +1,107 @@
+// Package sanitize runs a validation sweep over the assembled genesis
+// accounts before they are written into the genesis doc, catching classes
+// of chain-init failures that a plain string sort and a single supply total
+// check would miss.
+package sanitize
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Sanitize sorts accounts by account number (falling back to address on
+// ties, since account numbers are typically left at zero until InitChain
+// assigns them) and sorts each account's coin set, then validates:
+//
+//   - every account has strictly positive coins
+//   - every vesting account has EndTime > StartTime
+//   - every vesting account's OriginalVesting does not exceed its Coins,
+//     denom by denom
+//   - every address round-trips through bech32
+//   - the sum of Coins per denom matches expectedSupply exactly
+//
+// It returns the sorted accounts, or the first validation failure found.
+func Sanitize(accounts []gaia.GenesisAccount, expectedSupply map[string]sdk.Int) ([]gaia.GenesisAccount, error) {
+	for i := range accounts {
+		accounts[i].Coins = accounts[i].Coins.Sort()
+		accounts[i].OriginalVesting = accounts[i].OriginalVesting.Sort()
+	}
+
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].AccountNumber != accounts[j].AccountNumber {
+			return accounts[i].AccountNumber < accounts[j].AccountNumber
+		}
+		return strings.Compare(accounts[i].Address.String(), accounts[j].Address.String()) < 0
+	})
+
+	supply := make(map[string]sdk.Int)
+	for _, acc := range accounts {
+		if err := validateAccount(acc); err != nil {
+			return nil, err
+		}
+		for _, coin := range acc.Coins {
+			cur, ok := supply[coin.Denom]
+			if !ok {
+				cur = sdk.NewInt(0)
+			}
+			supply[coin.Denom] = cur.Add(coin.Amount)
+		}
+	}
+
+	if err := checkSupply(supply, expectedSupply); err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func validateAccount(acc gaia.GenesisAccount) error {
+	if _, err := sdk.AccAddressFromBech32(acc.Address.String()); err != nil {
+		return fmt.Errorf("sanitize: address %s does not round-trip through bech32: %v", acc.Address, err)
+	}
+
+	if !acc.Coins.IsValid() || !acc.Coins.IsAllPositive() {
+		return fmt.Errorf("sanitize: account %s has zero or negative coins: %s", acc.Address, acc.Coins)
+	}
+
+	if acc.OriginalVesting.Empty() {
+		return nil
+	}
+
+	if acc.EndTime <= acc.StartTime {
+		return fmt.Errorf("sanitize: vesting account %s has EndTime (%d) <= StartTime (%d)", acc.Address, acc.EndTime, acc.StartTime)
+	}
+
+	for _, coin := range acc.OriginalVesting {
+		held := acc.Coins.AmountOf(coin.Denom)
+		if coin.Amount.GT(held) {
+			return fmt.Errorf("sanitize: vesting account %s has OriginalVesting %s%s exceeding held Coins %s%s",
+				acc.Address, coin.Amount, coin.Denom, held, coin.Denom)
+		}
+	}
+	return nil
+}
+
+// checkSupply requires an exact match: every denom in expected must be
+// accounted for, and no denom may appear in got that wasn't expected.
+func checkSupply(got, expected map[string]sdk.Int) error {
+	for denom, want := range expected {
+		have, ok := got[denom]
+		if !ok {
+			have = sdk.NewInt(0)
+		}
+		if !have.Equal(want) {
+			return fmt.Errorf("sanitize: denom %s: expected supply %s, got %s", denom, want, have)
+		}
+	}
+	for denom := range got {
+		if _, ok := expected[denom]; !ok {
+			return fmt.Errorf("sanitize: denom %s present in accounts but missing from expected supply table", denom)
+		}
+	}
+	return nil
+}
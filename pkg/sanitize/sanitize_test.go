@@ -0,0 +1,120 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+
+	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func addr(s string) sdk.AccAddress { return sdk.AccAddress([]byte(s)) }
+
+func TestSanitize(t *testing.T) {
+	t.Run("happy path sorts and passes", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{Address: addr("addr-b"), Coins: sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(100))}},
+			{Address: addr("addr-a"), Coins: sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(50))}},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(150)}
+
+		got, err := Sanitize(accounts, expected)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d accounts, want 2", len(got))
+		}
+		// both account numbers are zero, so order falls back to the bech32 address
+		if got[0].Address.String() >= got[1].Address.String() {
+			t.Errorf("accounts not sorted by address: %s before %s", got[0].Address, got[1].Address)
+		}
+	})
+
+	t.Run("supply mismatch", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{Address: addr("addr-a"), Coins: sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(50))}},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(999)}
+
+		_, err := Sanitize(accounts, expected)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "expected supply") {
+			t.Errorf("error = %v, want a supply-mismatch error", err)
+		}
+	})
+
+	t.Run("unexpected denom", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{Address: addr("addr-a"), Coins: sdk.Coins{sdk.NewCoin("photon", sdk.NewInt(50))}},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(0)}
+
+		_, err := Sanitize(accounts, expected)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "missing from expected supply table") {
+			t.Errorf("error = %v, want an unexpected-denom error", err)
+		}
+	})
+
+	t.Run("negative coins", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{Address: addr("addr-a"), Coins: sdk.Coins{sdk.Coin{Denom: "uatom", Amount: sdk.NewInt(-5)}}},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(-5)}
+
+		_, err := Sanitize(accounts, expected)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "zero or negative coins") {
+			t.Errorf("error = %v, want a negative-coins error", err)
+		}
+	})
+
+	t.Run("vesting end before start", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{
+				Address:         addr("addr-a"),
+				Coins:           sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(100))},
+				OriginalVesting: sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(100))},
+				StartTime:       200,
+				EndTime:         100,
+			},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(100)}
+
+		_, err := Sanitize(accounts, expected)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "EndTime") {
+			t.Errorf("error = %v, want an EndTime/StartTime error", err)
+		}
+	})
+
+	t.Run("original vesting exceeds coins", func(t *testing.T) {
+		accounts := []gaia.GenesisAccount{
+			{
+				Address:         addr("addr-a"),
+				Coins:           sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(100))},
+				OriginalVesting: sdk.Coins{sdk.NewCoin("uatom", sdk.NewInt(500))},
+				StartTime:       100,
+				EndTime:         200,
+			},
+		}
+		expected := map[string]sdk.Int{"uatom": sdk.NewInt(100)}
+
+		_, err := Sanitize(accounts, expected)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "exceeding held Coins") {
+			t.Errorf("error = %v, want an OriginalVesting-exceeds-Coins error", err)
+		}
+	})
+}
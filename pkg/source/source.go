@@ -0,0 +1,441 @@
+// Package source loads contributor allocations from a pluggable set of
+// input formats (bech32/hex JSON, go-ethereum-style alloc files, CSV, and
+// signed claims against a prior chain's keys), so adding a new hard-spoon
+// or airdrop input format is a matter of registering a new Source rather
+// than editing the launch binary.
+package source
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+	cryptoAmino "github.com/tendermint/tendermint/crypto/encoding/amino"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/cosmos/launch/pkg"
+	"github.com/cosmos/launch/pkg/vesting"
+)
+
+// Allocation is one address's balance loaded from a contributor source,
+// before it's folded into the genesis accounts. Amount is an sdk.Dec, not
+// a float64, so merging allocations across sources never drifts from
+// repeated float addition.
+type Allocation struct {
+	Address string
+	Amount  sdk.Dec
+	Denom   string
+	// Vesting optionally requests a vesting schedule for this allocation;
+	// the zero value means a plain liquid account.
+	Vesting vesting.Grant
+}
+
+// Source loads a set of allocations from wherever it gets its data. Name
+// identifies the source in audit logs and error messages; Files lists the
+// input file(s) it read, for the reproducibility manifest; ExpectedSupply
+// is this source's declared contribution (if any) to the expected-supply
+// table the assembled accounts are checked against.
+type Source interface {
+	Load() ([]Allocation, error)
+	Name() string
+	Files() []string
+	ExpectedSupply() (denom string, amount sdk.Int, ok bool)
+}
+
+// Entry is one [[source]] table in launch.toml.
+type Entry struct {
+	Type     string `toml:"type"`
+	File     string `toml:"file"`
+	Denom    string `toml:"denom"`
+	Decimals int    `toml:"decimals"` // only meaningful for eth-alloc; defaults to 18
+	// ExpectSupply is this source's contribution to the expected-supply
+	// table sanitize.Sanitize checks the assembled accounts against, as an
+	// integer string in Denom's base unit (not "atoms" -- there's no
+	// fixed exponent to scale by for an arbitrary denom). Required for any
+	// source whose Denom isn't the chain's own atomDenomination, since
+	// that's the only denom main has an independent expected total for.
+	ExpectSupply string `toml:"expect_supply"`
+
+	// expectedAmount/expectedSet are ExpectSupply parsed once by
+	// LoadConfig, so a malformed value fails the run at load time rather
+	// than wherever expectedSupply() happens to get called from.
+	expectedAmount sdk.Int
+	expectedSet    bool
+}
+
+// expectedSupply returns this entry's declared expected-supply
+// contribution. ok is false if ExpectSupply was left empty, which is fine
+// for a source sharing the chain's own denom -- its expected total comes
+// from elsewhere.
+func (e Entry) expectedSupply() (denom string, amount sdk.Int, ok bool) {
+	return e.Denom, e.expectedAmount, e.expectedSet
+}
+
+// Config is the top-level shape of launch.toml.
+type Config struct {
+	Sources []Entry `toml:"source"`
+}
+
+// Registry maps the `type` string used in launch.toml to a constructor for
+// that source. New input formats register themselves here.
+var Registry = map[string]func(Entry) (Source, error){
+	"bech32-json":  newBech32JSON,
+	"hex-json":     newHexJSON,
+	"eth-alloc":    newEthAlloc,
+	"csv":          newCSV,
+	"signed-claim": newSignedClaim,
+}
+
+// LoadConfig parses launch.toml into a list of ready-to-run Sources.
+func LoadConfig(path string) ([]Source, error) {
+	var cfg Config
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return nil, fmt.Errorf("source: parsing %s: %v", path, err)
+	}
+
+	sources := make([]Source, 0, len(cfg.Sources))
+	for _, entry := range cfg.Sources {
+		build, ok := Registry[entry.Type]
+		if !ok {
+			return nil, fmt.Errorf("source: %s: unknown source type %q", entry.File, entry.Type)
+		}
+		if entry.Denom == "" {
+			entry.Denom = "uatom"
+		}
+		if entry.ExpectSupply != "" {
+			amt, ok := sdk.NewIntFromString(entry.ExpectSupply)
+			if !ok {
+				return nil, fmt.Errorf("source: %s: invalid expect_supply %q", entry.File, entry.ExpectSupply)
+			}
+			entry.expectedAmount = amt
+			entry.expectedSet = true
+		}
+		src, err := build(entry)
+		if err != nil {
+			return nil, fmt.Errorf("source: %s: %v", entry.File, err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// Merged is one address's combined allocation across all sources, plus the
+// names of the sources that contributed to it.
+type Merged struct {
+	Allocation
+	Sources []string
+}
+
+// Collect runs every source and merges allocations for addresses that show
+// up more than once, whether within a single source or across sources. A
+// denom or vesting-schedule conflict between two allocations for the same
+// address is a hard error; a plain duplicate (same address and denom) has
+// its amounts summed.
+//
+// The result is sorted by canonical bech32 address rather than returned
+// in source/map iteration order, so two runs over the same inputs produce
+// byte-identical output regardless of Go's randomized map iteration.
+func Collect(sources []Source) ([]Merged, error) {
+	byAddr := make(map[string]*Merged)
+
+	for _, src := range sources {
+		allocs, err := src.Load()
+		if err != nil {
+			return nil, fmt.Errorf("source: %s: %v", src.Name(), err)
+		}
+
+		seenInSource := make(map[string]struct{}, len(allocs))
+		for _, alloc := range allocs {
+			canon, err := sdk.AccAddressFromBech32(alloc.Address)
+			if err != nil {
+				return nil, fmt.Errorf("source: %s: %s: %v", src.Name(), alloc.Address, err)
+			}
+			alloc.Address = canon.String()
+
+			if _, dup := seenInSource[alloc.Address]; dup {
+				fmt.Println("Duplicate addr within source", src.Name(), alloc.Address)
+			}
+			seenInSource[alloc.Address] = struct{}{}
+
+			m, ok := byAddr[alloc.Address]
+			if !ok {
+				m = &Merged{Allocation: Allocation{Address: alloc.Address, Denom: alloc.Denom, Amount: sdk.ZeroDec()}}
+				byAddr[alloc.Address] = m
+			}
+			if alloc.Denom != m.Denom {
+				return nil, fmt.Errorf("source: %s: %s: denom %q conflicts with %q already accumulated", src.Name(), alloc.Address, alloc.Denom, m.Denom)
+			}
+			if alloc.Vesting.Schedule != "" {
+				if m.Vesting.Schedule != "" {
+					return nil, fmt.Errorf("source: %s: %s: vesting grant conflicts with one already set by an earlier allocation", src.Name(), alloc.Address)
+				}
+				m.Vesting = alloc.Vesting
+			}
+
+			m.Amount = m.Amount.Add(alloc.Amount)
+			m.Sources = append(m.Sources, src.Name())
+		}
+	}
+
+	addrs := make([]string, 0, len(byAddr))
+	for addr := range byAddr {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	merged := make([]Merged, 0, len(addrs))
+	for _, addr := range addrs {
+		sort.Strings(byAddr[addr].Sources)
+		merged = append(merged, *byAddr[addr])
+	}
+	return merged, nil
+}
+
+// WriteAuditLog writes the merged allocations, and which source(s)
+// contributed to each, as indented JSON to path. It's emitted alongside
+// penultimate_genesis.json so a reviewer can see where every coin in the
+// genesis came from without re-running the tool.
+func WriteAuditLog(path string, merged []Merged) error {
+	bz, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, bz, 0600)
+}
+
+//----------------------------------------------------------
+// bech32-json: a flat `{address: amount}` map, addresses already bech32.
+
+type bech32JSON struct{ entry Entry }
+
+func newBech32JSON(e Entry) (Source, error)                  { return bech32JSON{e}, nil }
+func (s bech32JSON) Name() string                            { return fmt.Sprintf("bech32-json:%s", s.entry.File) }
+func (s bech32JSON) Files() []string                         { return []string{s.entry.File} }
+func (s bech32JSON) ExpectedSupply() (string, sdk.Int, bool) { return s.entry.expectedSupply() }
+
+func (s bech32JSON) Load() ([]Allocation, error) {
+	// pkg.ObjToMap hands back a map[string]float64: this is the one
+	// boundary where an upstream float64 JSON number can't be avoided,
+	// since it's a pre-existing file format/loader this package doesn't
+	// own. Everything downstream of this line is sdk.Dec.
+	raw := pkg.ObjToMap(s.entry.File)
+	allocs := make([]Allocation, 0, len(raw))
+	for addr, amt := range raw {
+		allocs = append(allocs, Allocation{Address: addr, Amount: decFromFloat(amt), Denom: s.entry.Denom})
+	}
+	return allocs, nil
+}
+
+//----------------------------------------------------------
+// hex-json: a flat `{address: amount}` map, addresses in hex.
+
+type hexJSON struct{ entry Entry }
+
+func newHexJSON(e Entry) (Source, error)                  { return hexJSON{e}, nil }
+func (s hexJSON) Name() string                            { return fmt.Sprintf("hex-json:%s", s.entry.File) }
+func (s hexJSON) Files() []string                         { return []string{s.entry.File} }
+func (s hexJSON) ExpectedSupply() (string, sdk.Int, bool) { return s.entry.expectedSupply() }
+
+func (s hexJSON) Load() ([]Allocation, error) {
+	raw := pkg.ObjToMap(s.entry.File)
+	allocs := make([]Allocation, 0, len(raw))
+	for addr, amt := range raw {
+		bech32Addr, err := sdk.AccAddressFromHex(addr)
+		if err != nil {
+			return nil, fmt.Errorf("hex-json: %s: %v", addr, err)
+		}
+		allocs = append(allocs, Allocation{Address: bech32Addr.String(), Amount: decFromFloat(amt), Denom: s.entry.Denom})
+	}
+	return allocs, nil
+}
+
+// decFromFloat converts a float64 already parsed by an upstream JSON
+// loader into an sdk.Dec, round-tripping through its decimal string
+// representation rather than sdk.Dec's own (binary) float constructor so
+// the value we store matches what's on the page, not an IEEE-754 ghost
+// of it.
+func decFromFloat(f float64) sdk.Dec {
+	return sdk.MustNewDecFromStr(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+//----------------------------------------------------------
+// eth-alloc: a go-ethereum-style GenesisAlloc, `{address: {balance: "0x.."}}`.
+
+type ethAllocAccount struct {
+	Balance string `json:"balance"`
+}
+
+type ethAlloc struct{ entry Entry }
+
+func newEthAlloc(e Entry) (Source, error) {
+	if e.Decimals == 0 {
+		e.Decimals = 18 // matches go-ethereum's wei precision
+	}
+	return ethAlloc{e}, nil
+}
+func (s ethAlloc) Name() string                            { return fmt.Sprintf("eth-alloc:%s", s.entry.File) }
+func (s ethAlloc) Files() []string                         { return []string{s.entry.File} }
+func (s ethAlloc) ExpectedSupply() (string, sdk.Int, bool) { return s.entry.expectedSupply() }
+
+func (s ethAlloc) Load() ([]Allocation, error) {
+	bz, err := ioutil.ReadFile(s.entry.File)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]ethAllocAccount
+	if err := json.Unmarshal(bz, &raw); err != nil {
+		return nil, fmt.Errorf("eth-alloc: %s: %v", s.entry.File, err)
+	}
+
+	allocs := make([]Allocation, 0, len(raw))
+	for ethAddr, account := range raw {
+		wei, ok := new(big.Int).SetString(strings.TrimPrefix(account.Balance, "0x"), 16)
+		if !ok {
+			return nil, fmt.Errorf("eth-alloc: %s: invalid hex balance %q", ethAddr, account.Balance)
+		}
+		// NewDecFromBigIntWithPrec divides wei by 10^Decimals exactly,
+		// with no float64 involved at any point.
+		amt := sdk.NewDecFromBigIntWithPrec(wei, int64(s.entry.Decimals))
+
+		bz, err := hex.DecodeString(strings.TrimPrefix(ethAddr, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("eth-alloc: %s: %v", ethAddr, err)
+		}
+		bech32Addr, err := sdk.AccAddressFromHex(hex.EncodeToString(bz))
+		if err != nil {
+			return nil, fmt.Errorf("eth-alloc: %s: %v", ethAddr, err)
+		}
+
+		allocs = append(allocs, Allocation{Address: bech32Addr.String(), Amount: amt, Denom: s.entry.Denom})
+	}
+	return allocs, nil
+}
+
+//----------------------------------------------------------
+// csv: columns address,amount,denom,vesting (denom and vesting optional).
+
+type csvSource struct{ entry Entry }
+
+func newCSV(e Entry) (Source, error)                        { return csvSource{e}, nil }
+func (s csvSource) Name() string                            { return fmt.Sprintf("csv:%s", s.entry.File) }
+func (s csvSource) Files() []string                         { return []string{s.entry.File} }
+func (s csvSource) ExpectedSupply() (string, sdk.Int, bool) { return s.entry.expectedSupply() }
+
+func (s csvSource) Load() ([]Allocation, error) {
+	f, err := os.Open(s.entry.File)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv: %s: %v", s.entry.File, err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	col := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"address", "amount"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("csv: %s: missing required column %q", s.entry.File, required)
+		}
+	}
+
+	allocs := make([]Allocation, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		amt, err := sdk.NewDecFromStr(row[col["amount"]])
+		if err != nil {
+			return nil, fmt.Errorf("csv: %s: bad amount %q: %v", s.entry.File, row[col["amount"]], err)
+		}
+
+		alloc := Allocation{
+			Address: row[col["address"]],
+			Amount:  amt,
+			Denom:   s.entry.Denom,
+		}
+		if i, ok := col["denom"]; ok && row[i] != "" {
+			alloc.Denom = row[i]
+		}
+		if i, ok := col["vesting"]; ok && row[i] != "" {
+			// the CSV format only expresses a cliff: the vesting column
+			// is the named time anchor it unlocks at.
+			alloc.Vesting = vesting.Grant{Schedule: vesting.ScheduleDelayed, End: row[i]}
+		}
+		allocs = append(allocs, alloc)
+	}
+	return allocs, nil
+}
+
+//----------------------------------------------------------
+// signed-claim: an address plus a signature proving control of the key
+// behind an address on a prior chain.
+
+const claimMessageFmt = "I control the key behind %s and claim its balance on the new chain."
+
+type claimRow struct {
+	Address   string  `json:"addr"`
+	Amount    sdk.Dec `json:"amount"`
+	PubKey    string  `json:"pub_key"`   // amino JSON-encoded crypto.PubKey
+	Signature string  `json:"signature"` // base64
+}
+
+type signedClaim struct{ entry Entry }
+
+func newSignedClaim(e Entry) (Source, error)                  { return signedClaim{e}, nil }
+func (s signedClaim) Name() string                            { return fmt.Sprintf("signed-claim:%s", s.entry.File) }
+func (s signedClaim) Files() []string                         { return []string{s.entry.File} }
+func (s signedClaim) ExpectedSupply() (string, sdk.Int, bool) { return s.entry.expectedSupply() }
+
+func (s signedClaim) Load() ([]Allocation, error) {
+	bz, err := ioutil.ReadFile(s.entry.File)
+	if err != nil {
+		return nil, err
+	}
+	var rows []claimRow
+	if err := json.Unmarshal(bz, &rows); err != nil {
+		return nil, fmt.Errorf("signed-claim: %s: %v", s.entry.File, err)
+	}
+
+	cdc := amino.NewCodec()
+	cryptoAmino.RegisterAmino(cdc)
+	allocs := make([]Allocation, 0, len(rows))
+	for _, row := range rows {
+		var pubKey crypto.PubKey
+		if err := cdc.UnmarshalJSON([]byte(row.PubKey), &pubKey); err != nil {
+			return nil, fmt.Errorf("signed-claim: %s: bad pub_key: %v", row.Address, err)
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(row.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("signed-claim: %s: bad signature encoding: %v", row.Address, err)
+		}
+
+		msg := []byte(fmt.Sprintf(claimMessageFmt, row.Address))
+		if !pubKey.VerifyBytes(msg, sig) {
+			return nil, fmt.Errorf("signed-claim: %s: signature does not verify against pub_key", row.Address)
+		}
+		if sdk.AccAddress(pubKey.Address()).String() != row.Address {
+			return nil, fmt.Errorf("signed-claim: %s: pub_key does not derive the claimed address", row.Address)
+		}
+
+		allocs = append(allocs, Allocation{Address: row.Address, Amount: row.Amount, Denom: s.entry.Denom})
+	}
+	return allocs, nil
+}
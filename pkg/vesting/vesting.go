@@ -0,0 +1,199 @@
+// Package vesting builds gaia.GenesisAccount vesting schedules from a
+// config-driven grant description, instead of hard-coding one cliff and one
+// continuous schedule in main. It covers the SDK's three vesting account
+// shapes: delayed (cliff), continuous, and periodic.
+package vesting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	gaia "github.com/cosmos/cosmos-sdk/cmd/gaia/app"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// Schedule selects which vesting account shape a grant produces.
+type Schedule string
+
+const (
+	// ScheduleDelayed is a cliff: all coins are locked until End.
+	ScheduleDelayed Schedule = "delayed"
+	// ScheduleContinuous unlocks linearly between Start and End.
+	ScheduleContinuous Schedule = "continuous"
+	// SchedulePeriodic unlocks in discrete Periods, each some length of
+	// time after the last (or after Start, for the first).
+	//
+	// This depends on gaia.Period / gaia.GenesisAccount.VestingPeriods,
+	// which landed with the SDK's periodic vesting account type; confirm
+	// the vendored cosmos-sdk at this repo's pinned commit has it before
+	// relying on this schedule, since an older pin won't have the field.
+	SchedulePeriodic Schedule = "periodic"
+)
+
+// Period is one step of a periodic vesting schedule: Amount unlocks Length
+// seconds after the previous period (or after Start, for the first).
+//
+// Amount is an sdk.Dec, not a float64: summing periods against the
+// grant's total has to be exact, and float64 can't guarantee that.
+type Period struct {
+	Length int64   `json:"length"`
+	Amount sdk.Dec `json:"amount"`
+}
+
+// Grant is the JSON shape of one row in a vesting input file (e.g.
+// accounts/aib/employees.json, accounts/aib/multisig.json).
+type Grant struct {
+	Address  string   `json:"addr"`
+	Amount   sdk.Dec  `json:"amount"`
+	Schedule Schedule `json:"schedule"`
+	// Start and End name anchors registered on the Anchors passed to
+	// Build; empty Start means genesis.
+	Start   string   `json:"start,omitempty"`
+	End     string   `json:"end,omitempty"`
+	Periods []Period `json:"periods,omitempty"`
+}
+
+// ParseAmount decodes a grant's "amount" field, accepting either an
+// sdk.Dec-style quoted decimal string or a bare JSON number. The original
+// accounts/aib/*.json files predate sdk.Dec and still carry amount as a
+// number, which sdk.Dec's own UnmarshalJSON rejects outright; a bare
+// number is round-tripped through its decimal string the same way
+// pkg/source's decFromFloat does, so callers that embed Grant (main's
+// Employee, Multisig) can shadow the "amount" key with a json.RawMessage
+// and parse it through here instead.
+func ParseAmount(raw json.RawMessage) (sdk.Dec, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return sdk.Dec{}, fmt.Errorf("vesting: missing amount")
+	}
+	if trimmed[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return sdk.Dec{}, err
+		}
+		return sdk.NewDecFromStr(s)
+	}
+
+	f, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil {
+		return sdk.Dec{}, err
+	}
+	return sdk.NewDecFromStr(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Anchors centralizes the named time offsets vesting grants are anchored
+// to, so new grant types can reference a new anchor without touching main
+// or the builder. It replaces the old timeGenesisTwoMonths-style globals.
+type Anchors struct {
+	genesis time.Time
+	named   map[string]time.Time
+}
+
+// NewAnchors seeds the anchor set with genesis itself plus the offsets the
+// original AiB grants used. Resolve accepts any of these names (or "" for
+// genesis) and fails loudly on anything else.
+func NewAnchors(genesis time.Time) *Anchors {
+	return &Anchors{
+		genesis: genesis,
+		named: map[string]time.Time{
+			"genesis":    genesis,
+			"two_months": genesis.AddDate(0, 2, 0),
+			"one_year":   genesis.AddDate(1, 0, 0),
+			"two_years":  genesis.AddDate(2, 0, 0),
+		},
+	}
+}
+
+// Resolve looks up a named anchor; an empty name resolves to genesis.
+func (a *Anchors) Resolve(name string) (time.Time, error) {
+	if name == "" {
+		return a.genesis, nil
+	}
+	t, ok := a.named[name]
+	if !ok {
+		return time.Time{}, fmt.Errorf("vesting: unknown time anchor %q", name)
+	}
+	return t, nil
+}
+
+// Build converts a Grant into the gaia.GenesisAccount for addr. newCoins
+// turns an sdk.Dec atom amount into sdk.Coins; callers pass in the same
+// denomination/precision helper main uses elsewhere so this package stays
+// agnostic of both.
+func Build(grant Grant, anchors *Anchors, addr sdk.AccAddress, newCoins func(sdk.Dec) sdk.Coins) (gaia.GenesisAccount, error) {
+	coins := newCoins(grant.Amount)
+
+	switch grant.Schedule {
+	case ScheduleDelayed:
+		end, err := anchors.Resolve(grant.End)
+		if err != nil {
+			return gaia.GenesisAccount{}, err
+		}
+		return gaia.GenesisAccount{
+			Address:         addr,
+			Coins:           coins,
+			OriginalVesting: coins,
+			EndTime:         end.Unix(),
+		}, nil
+
+	case ScheduleContinuous:
+		start, err := anchors.Resolve(grant.Start)
+		if err != nil {
+			return gaia.GenesisAccount{}, err
+		}
+		end, err := anchors.Resolve(grant.End)
+		if err != nil {
+			return gaia.GenesisAccount{}, err
+		}
+		return gaia.GenesisAccount{
+			Address:         addr,
+			Coins:           coins,
+			OriginalVesting: coins,
+			StartTime:       start.Unix(),
+			EndTime:         end.Unix(),
+		}, nil
+
+	case SchedulePeriodic:
+		return buildPeriodic(grant, anchors, addr, coins, newCoins)
+
+	default:
+		return gaia.GenesisAccount{}, fmt.Errorf("vesting: unknown schedule %q for %s", grant.Schedule, grant.Address)
+	}
+}
+
+func buildPeriodic(grant Grant, anchors *Anchors, addr sdk.AccAddress, coins sdk.Coins, newCoins func(sdk.Dec) sdk.Coins) (gaia.GenesisAccount, error) {
+	if len(grant.Periods) == 0 {
+		return gaia.GenesisAccount{}, fmt.Errorf("vesting: periodic grant for %s has no periods", grant.Address)
+	}
+
+	start, err := anchors.Resolve(grant.Start)
+	if err != nil {
+		return gaia.GenesisAccount{}, err
+	}
+
+	periods := make([]gaia.Period, 0, len(grant.Periods))
+	total := sdk.Coins{}
+	end := start
+	for _, step := range grant.Periods {
+		stepCoins := newCoins(step.Amount)
+		periods = append(periods, gaia.Period{Length: step.Length, Amount: stepCoins})
+		total = total.Add(stepCoins)
+		end = end.Add(time.Duration(step.Length) * time.Second)
+	}
+
+	if !total.IsEqual(coins) {
+		return gaia.GenesisAccount{}, fmt.Errorf("vesting: periods for %s sum to %s, want %s", grant.Address, total, coins)
+	}
+
+	return gaia.GenesisAccount{
+		Address:         addr,
+		Coins:           coins,
+		OriginalVesting: coins,
+		StartTime:       start.Unix(),
+		EndTime:         end.Unix(),
+		VestingPeriods:  periods,
+	}, nil
+}
@@ -0,0 +1,132 @@
+package vesting
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var testGenesis = time.Date(2019, 3, 13, 23, 0, 0, 0, time.UTC)
+
+func testCoins(amt sdk.Dec) sdk.Coins {
+	return sdk.Coins{sdk.Coin{Denom: "uatom", Amount: amt.TruncateInt()}}
+}
+
+func TestBuildSchedules(t *testing.T) {
+	addr := sdk.AccAddress([]byte("test-address-000001"))
+	anchors := NewAnchors(testGenesis)
+
+	t.Run("delayed", func(t *testing.T) {
+		grant := Grant{Address: "addr", Amount: sdk.NewDec(100), Schedule: ScheduleDelayed, End: "one_year"}
+		acc, err := Build(grant, anchors, addr, testCoins)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wantEnd := anchors.named["one_year"].Unix()
+		if acc.EndTime != wantEnd {
+			t.Errorf("EndTime = %d, want %d", acc.EndTime, wantEnd)
+		}
+		if !acc.OriginalVesting.IsEqual(acc.Coins) {
+			t.Errorf("OriginalVesting = %s, want all of Coins %s locked", acc.OriginalVesting, acc.Coins)
+		}
+	})
+
+	t.Run("continuous", func(t *testing.T) {
+		grant := Grant{Address: "addr", Amount: sdk.NewDec(100), Schedule: ScheduleContinuous, Start: "two_months", End: "two_years"}
+		acc, err := Build(grant, anchors, addr, testCoins)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wantStart := anchors.named["two_months"].Unix()
+		wantEnd := anchors.named["two_years"].Unix()
+		if acc.StartTime != wantStart {
+			t.Errorf("StartTime = %d, want %d", acc.StartTime, wantStart)
+		}
+		if acc.EndTime != wantEnd {
+			t.Errorf("EndTime = %d, want %d", acc.EndTime, wantEnd)
+		}
+	})
+
+	t.Run("periodic", func(t *testing.T) {
+		grant := Grant{
+			Address:  "addr",
+			Amount:   sdk.NewDec(100),
+			Schedule: SchedulePeriodic,
+			Periods: []Period{
+				{Length: 100, Amount: sdk.NewDec(40)},
+				{Length: 200, Amount: sdk.NewDec(60)},
+			},
+		}
+		acc, err := Build(grant, anchors, addr, testCoins)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(acc.VestingPeriods) != 2 {
+			t.Fatalf("got %d periods, want 2", len(acc.VestingPeriods))
+		}
+		wantEnd := testGenesis.Add(300 * time.Second).Unix()
+		if acc.EndTime != wantEnd {
+			t.Errorf("EndTime = %d, want %d", acc.EndTime, wantEnd)
+		}
+	})
+
+	t.Run("periodic sum mismatch", func(t *testing.T) {
+		grant := Grant{
+			Address:  "addr",
+			Amount:   sdk.NewDec(100),
+			Schedule: SchedulePeriodic,
+			Periods: []Period{
+				{Length: 100, Amount: sdk.NewDec(40)},
+				{Length: 200, Amount: sdk.NewDec(50)}, // 90 != 100
+			},
+		}
+		_, err := Build(grant, anchors, addr, testCoins)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "sum to") {
+			t.Errorf("error = %v, want a period-sum mismatch error", err)
+		}
+	})
+
+	t.Run("unknown anchor", func(t *testing.T) {
+		grant := Grant{Address: "addr", Amount: sdk.NewDec(100), Schedule: ScheduleDelayed, End: "next_tuesday"}
+		_, err := Build(grant, anchors, addr, testCoins)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+		if !strings.Contains(err.Error(), "unknown time anchor") {
+			t.Errorf("error = %v, want an unknown-anchor error", err)
+		}
+	})
+
+	t.Run("unknown schedule", func(t *testing.T) {
+		grant := Grant{Address: "addr", Amount: sdk.NewDec(100), Schedule: "cliff-and-linear"}
+		_, err := Build(grant, anchors, addr, testCoins)
+		if err == nil {
+			t.Fatal("expected an error, got nil")
+		}
+	})
+}
+
+func TestAnchorsResolve(t *testing.T) {
+	anchors := NewAnchors(testGenesis)
+
+	got, err := anchors.Resolve("")
+	if err != nil {
+		t.Fatalf("unexpected error resolving empty name: %v", err)
+	}
+	if !got.Equal(testGenesis) {
+		t.Errorf("Resolve(\"\") = %v, want genesis %v", got, testGenesis)
+	}
+
+	if _, err := anchors.Resolve("one_year"); err != nil {
+		t.Errorf("unexpected error resolving a registered anchor: %v", err)
+	}
+
+	if _, err := anchors.Resolve("does_not_exist"); err == nil {
+		t.Error("expected an error resolving an unregistered anchor, got nil")
+	}
+}